@@ -0,0 +1,34 @@
+// Package allocationchange defines the operations a CommitRequest applies
+// to an allocation's rootRef once a blobber's current reference path has
+// been fetched, and the bookkeeping (size, affected path) the commit
+// worker needs to build the next WriteMarker.
+package allocationchange
+
+import (
+	"github.com/0chain/gosdk/zboxcore/fileref"
+)
+
+// AllocationChange is one pending modification to an allocation: a file
+// upload, delete, rename, or (see DeltaUpdateChange) a partial update.
+// CommitRequest.processCommit applies every queued change to rootRef
+// before signing the resulting WriteMarker.
+type AllocationChange interface {
+	// ProcessChange applies the change to rootRef, mutating the tree so
+	// rootRef.CalculateHash reflects the post-change allocation root.
+	ProcessChange(rootRef *fileref.Ref) error
+	// GetAffectedPath returns the remote path this change touches, used
+	// to request the blobber's reference path for that subtree.
+	GetAffectedPath() string
+	// GetSize returns the change in allocation size this change
+	// contributes to the WriteMarker.
+	GetSize() int64
+}
+
+// change holds the fields common to every AllocationChange implementation.
+type change struct {
+	AllocationID string
+	Size         int64
+}
+
+// GetSize implements AllocationChange.
+func (c *change) GetSize() int64 { return c.Size }
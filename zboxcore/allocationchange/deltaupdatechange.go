@@ -0,0 +1,96 @@
+package allocationchange
+
+import (
+	"fmt"
+
+	"github.com/0chain/gosdk/zboxcore/deltaupload"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+)
+
+// DeltaUpdateChange updates an existing file in place from a delta script
+// produced by deltaupload.ComputeDelta: COPY ops reuse a chunk the blobber
+// already has (by its remote index), INSERT ops carry the new local bytes
+// as ComputeDelta emits them. Applying it only changes the target FileRef's hash/size, so
+// unchanged sibling files never get re-hashed or re-sent.
+//
+// Not yet wired in: nothing constructs a DeltaUpdateChange and queues it on
+// a CommitRequest - that has to happen in the main upload loop, which
+// decides full-vs-delta per file and isn't part of this tree slice. Until
+// that caller exists, this is an unused primitive.
+type DeltaUpdateChange struct {
+	change
+	Path        string
+	NewFileHash string
+	NewFileSize int64
+	RemoteIndex []deltaupload.ChunkHash
+	Ops         []deltaupload.Op
+}
+
+// NewDeltaUpdateChange builds a DeltaUpdateChange for path. newFileHash is
+// the recomputed hash of the full reconstructed file (COPY ops replayed
+// against RemoteIndex plus the new INSERT bytes), which is what a blobber
+// verifies the delta against: it already independently knows the bytes for
+// every COPY op, so it can confirm the reconstructed file matches
+// newFileHash without trusting the client's op script.
+func NewDeltaUpdateChange(allocationID, path, newFileHash string, newFileSize int64, remoteIndex []deltaupload.ChunkHash, ops []deltaupload.Op) *DeltaUpdateChange {
+	var insertBytes int64
+	for _, op := range ops {
+		if op.Kind == deltaupload.OpInsert {
+			insertBytes += op.Len
+		}
+	}
+	return &DeltaUpdateChange{
+		change:      change{AllocationID: allocationID, Size: insertBytes},
+		Path:        path,
+		NewFileHash: newFileHash,
+		NewFileSize: newFileSize,
+		RemoteIndex: remoteIndex,
+		Ops:         ops,
+	}
+}
+
+// GetAffectedPath implements AllocationChange.
+func (ch *DeltaUpdateChange) GetAffectedPath() string {
+	return ch.Path
+}
+
+// ProcessChange locates the existing FileRef at ch.Path in rootRef and
+// updates its size and hash to reflect the delta, then recalculates the
+// allocation root hash. The file must already exist in rootRef - a delta
+// update against a path with no prior reference path is a bug upstream in
+// the caller, not something this method can repair.
+func (ch *DeltaUpdateChange) ProcessChange(rootRef *fileref.Ref) error {
+	fileRef, err := findFileRef(rootRef, ch.Path)
+	if err != nil {
+		return err
+	}
+	fileRef.ActualFileHash = ch.NewFileHash
+	fileRef.Size = ch.NewFileSize
+	rootRef.CalculateHash()
+	return nil
+}
+
+// findFileRef walks root's children looking for path. root itself is
+// never the match: callers always pass the allocation's directory tree
+// root (from Allocation.GetRefs/GetDirTree), which is always a directory,
+// and fileref.Ref has no accessor that could turn it into a *FileRef even
+// if it did hold one.
+func findFileRef(root *fileref.Ref, path string) (*fileref.FileRef, error) {
+	for _, child := range root.Children {
+		if child.GetType() == fileref.FILE && child.GetPath() == path {
+			fr, ok := child.(*fileref.FileRef)
+			if !ok {
+				return nil, fmt.Errorf("delta update: %s is not a file", path)
+			}
+			return fr, nil
+		}
+		if child.GetType() == fileref.DIRECTORY {
+			if dirRef, ok := child.(*fileref.Ref); ok {
+				if fr, err := findFileRef(dirRef, path); err == nil {
+					return fr, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("delta update: no reference found for path %s", path)
+}
@@ -0,0 +1,249 @@
+// Package deltaupload computes a block-level delta between a local file
+// and the chunk hashes a quorum of blobbers already hold for that path, so
+// an update only re-uploads the chunks that actually changed. The scan
+// borrows the rsync/packfile delta approach: a rolling weak checksum slides
+// byte-by-byte over the local file to locate candidate matches at arbitrary
+// offsets (not just chunkSize-aligned ones), which a strong hash then
+// confirms before emitting a COPY op and resyncing past the matched chunk;
+// everything else becomes an INSERT of new bytes.
+//
+// Not yet wired in: nothing in this tree slice fetches a quorum's remote
+// chunk hashes, decides full-vs-delta per upload, or turns this package's
+// output into an allocationchange.DeltaUpdateChange that processCommit
+// applies. That integration belongs in the main upload loop (sdk/upload.go
+// in the full gosdk tree), which is not part of this checkout. Until that
+// caller exists, ComputeDelta/BuildRemoteIndex/ShouldFallbackToFullUpload
+// are unused primitives, not a landed feature.
+package deltaupload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// FallbackRatio is the fraction of the file size above which the delta is
+// considered not worth sending; callers should fall back to a full
+// erasure-encoded upload instead. This mirrors the heuristic pack delta
+// selection uses to decide whether a delta candidate is worth keeping.
+const FallbackRatio = 0.70
+
+// ChunkHash is one block's weak and strong hash, as already committed on a
+// blobber for the target path. Both are needed to relocate the chunk in a
+// changed local file: Weak lets the scan reject most offsets in O(1), Hash
+// confirms an actual match before it is trusted. The blobber (not this
+// tree slice) is responsible for computing Weak the same way WeakChecksum
+// does here, over the same chunk bytes it hashed into Hash.
+type ChunkHash struct {
+	Index int64
+	Hash  string
+	Weak  uint32
+}
+
+// OpKind distinguishes the two delta script operations.
+type OpKind int
+
+const (
+	// OpCopy references an unchanged remote chunk by index; no bytes for
+	// it need to travel over the wire.
+	OpCopy OpKind = iota
+	// OpInsert carries new bytes that do not match any remote chunk.
+	OpInsert
+)
+
+// Op is one entry in the delta script produced by ComputeDelta. COPY ops
+// carry RemoteChunkIndex/Len; INSERT ops carry Data.
+type Op struct {
+	Kind             OpKind
+	RemoteChunkIndex int64
+	Len              int64
+	Data             []byte
+}
+
+// remoteIndex maps a chunk's weak checksum to the candidate remote chunks
+// sharing it, so the scan can reject most offsets in O(1) before paying
+// for the strong hash comparison.
+type remoteIndex struct {
+	chunkSize int64
+	byWeak    map[uint32][]ChunkHash
+}
+
+// BuildRemoteIndex indexes the remote per-chunk hashes by their
+// blobber-computed weak checksum so the local scan can look up candidate
+// matches in O(1) per offset.
+func BuildRemoteIndex(chunkSize int64, hashes []ChunkHash) *remoteIndex {
+	idx := &remoteIndex{chunkSize: chunkSize, byWeak: make(map[uint32][]ChunkHash, len(hashes))}
+	for _, h := range hashes {
+		idx.byWeak[h.Weak] = append(idx.byWeak[h.Weak], h)
+	}
+	return idx
+}
+
+// rollingMod is the modulus the weak checksum's two running sums wrap at,
+// matching the classic rsync rolling checksum (Tridgell's adler32-style
+// weak hash).
+const rollingMod = 1 << 16
+
+// rollingSums computes the two running sums a rolling checksum is built
+// from, from scratch over data.
+func rollingSums(data []byte) (a, b uint32) {
+	n := uint32(len(data))
+	for i, x := range data {
+		a += uint32(x)
+		b += (n - uint32(i)) * uint32(x)
+	}
+	return a % rollingMod, b % rollingMod
+}
+
+// WeakChecksum computes the rolling checksum of data from scratch. Blobbers
+// use this (over the same bytes they compute Hash from) to populate
+// ChunkHash.Weak; ComputeDelta seeds its rollingWindow (and re-seeds it
+// after every confirmed match) from the same rollingSums.
+func WeakChecksum(data []byte) uint32 {
+	a, b := rollingSums(data)
+	return a | (b << 16)
+}
+
+// rollingWindow maintains a's and b's running sums for a fixed-size window
+// over a byte slice so sliding the window by one byte is O(1), instead of
+// recomputing the checksum over the whole window at every offset.
+type rollingWindow struct {
+	a, b uint32
+	size uint32
+}
+
+func newRollingWindow(data []byte) *rollingWindow {
+	a, b := rollingSums(data)
+	return &rollingWindow{a: a, b: b, size: uint32(len(data))}
+}
+
+func (w *rollingWindow) checksum() uint32 { return w.a | (w.b << 16) }
+
+// roll slides the window forward by one byte: out leaves at the trailing
+// edge, in enters at the leading edge.
+func (w *rollingWindow) roll(out, in byte) {
+	a := (uint64(w.a) + rollingMod - uint64(out)%rollingMod + uint64(in)) % rollingMod
+	b := (uint64(w.b) + rollingMod*rollingMod - (uint64(w.size)*uint64(out))%rollingMod + a) % rollingMod
+	w.a, w.b = uint32(a), uint32(b)
+}
+
+func strongHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ComputeDelta scans local against the remote chunk index and returns the
+// op script needed to reconstruct local from remote. It buffers the whole
+// of local in memory: relocating a shifted chunk (e.g. after a single byte
+// was inserted earlier in the file) requires probing windows that start at
+// arbitrary byte offsets, not just chunkSize-aligned ones, which rules out
+// a single forward streaming pass.
+func ComputeDelta(local io.Reader, chunkSize int64, idx *remoteIndex) ([]Op, int64, error) {
+	data, err := io.ReadAll(local)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ops []Op
+	var insertBytes int64
+	var pending bytes.Buffer
+	flush := func() {
+		if pending.Len() == 0 {
+			return
+		}
+		b := make([]byte, pending.Len())
+		copy(b, pending.Bytes())
+		ops = append(ops, Op{Kind: OpInsert, Len: int64(len(b)), Data: b})
+		insertBytes += int64(len(b))
+		pending.Reset()
+	}
+
+	window := int(chunkSize)
+	n := len(data)
+	if window <= 0 || window > n {
+		// No full window fits (tiny file, or a non-positive chunk size);
+		// nothing to match against, so the whole file is new bytes.
+		pending.Write(data)
+		flush()
+		return ops, insertBytes, nil
+	}
+
+	pos := 0
+	rw := newRollingWindow(data[pos : pos+window])
+	for {
+		if match, ok := lookup(idx, rw.checksum(), data[pos:pos+window]); ok {
+			flush()
+			ops = append(ops, Op{Kind: OpCopy, RemoteChunkIndex: match.Index, Len: int64(window)})
+			pos += window
+			if pos+window > n {
+				break
+			}
+			rw = newRollingWindow(data[pos : pos+window])
+			continue
+		}
+		pending.WriteByte(data[pos])
+		pos++
+		if pos+window > n {
+			break
+		}
+		rw.roll(data[pos-1], data[pos+window-1])
+	}
+	pending.Write(data[pos:])
+	flush()
+
+	return coalesceInserts(ops), insertBytes, nil
+}
+
+// lookup confirms a weak-checksum candidate against window's strong hash,
+// since the weak checksum alone can collide between unrelated chunks.
+func lookup(idx *remoteIndex, weak uint32, window []byte) (ChunkHash, bool) {
+	if len(idx.byWeak[weak]) == 0 {
+		return ChunkHash{}, false
+	}
+	strong := strongHash(window)
+	for _, c := range idx.byWeak[weak] {
+		if c.Hash == strong {
+			return c, true
+		}
+	}
+	return ChunkHash{}, false
+}
+
+// coalesceInserts merges each run of adjacent INSERT ops into one op so
+// the caller can erasure-encode a single contiguous buffer per gap instead
+// of one call per CHUNK_SIZE block. The merged op is not split or padded
+// to CHUNK_SIZE boundaries.
+func coalesceInserts(ops []Op) []Op {
+	var out []Op
+	var pending bytes.Buffer
+	flush := func() {
+		if pending.Len() == 0 {
+			return
+		}
+		data := make([]byte, pending.Len())
+		copy(data, pending.Bytes())
+		out = append(out, Op{Kind: OpInsert, Len: int64(len(data)), Data: data})
+		pending.Reset()
+	}
+	for _, op := range ops {
+		if op.Kind == OpInsert {
+			pending.Write(op.Data)
+			continue
+		}
+		flush()
+		out = append(out, op)
+	}
+	flush()
+	return out
+}
+
+// ShouldFallbackToFullUpload reports whether the computed delta is large
+// enough, relative to fileSize, that sending it is not worth the savings
+// over a plain full upload.
+func ShouldFallbackToFullUpload(insertBytes, fileSize int64) bool {
+	if fileSize <= 0 {
+		return false
+	}
+	return float64(insertBytes)/float64(fileSize) > FallbackRatio
+}
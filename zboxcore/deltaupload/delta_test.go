@@ -0,0 +1,103 @@
+package deltaupload
+
+import (
+	"bytes"
+	"testing"
+)
+
+func remoteHashesFor(t *testing.T, chunkSize int64, content []byte) []ChunkHash {
+	t.Helper()
+	var hashes []ChunkHash
+	var idx int64
+	for off := 0; off < len(content); off += int(chunkSize) {
+		end := off + int(chunkSize)
+		if end > len(content) {
+			end = len(content)
+		}
+		hashes = append(hashes, ChunkHash{Index: idx, Hash: strongHash(content[off:end]), Weak: WeakChecksum(content[off:end])})
+		idx++
+	}
+	return hashes
+}
+
+func TestComputeDeltaUnchangedFileIsAllCopy(t *testing.T) {
+	chunkSize := int64(8)
+	content := []byte("aaaaaaaabbbbbbbbcccccccc")
+	remote := remoteHashesFor(t, chunkSize, content)
+	idx := BuildRemoteIndex(chunkSize, remote)
+
+	ops, insertBytes, err := ComputeDelta(bytes.NewReader(content), chunkSize, idx)
+	if err != nil {
+		t.Fatalf("ComputeDelta: %v", err)
+	}
+	if insertBytes != 0 {
+		t.Fatalf("expected no inserted bytes for an unchanged file, got %d", insertBytes)
+	}
+	for _, op := range ops {
+		if op.Kind != OpCopy {
+			t.Fatalf("expected only COPY ops, found %+v", op)
+		}
+	}
+}
+
+func TestComputeDeltaChangedChunkIsInsert(t *testing.T) {
+	chunkSize := int64(8)
+	remote := []byte("aaaaaaaabbbbbbbbcccccccc")
+	local := []byte("aaaaaaaaXXXXXXXXcccccccc")
+	idx := BuildRemoteIndex(chunkSize, remoteHashesFor(t, chunkSize, remote))
+
+	ops, insertBytes, err := ComputeDelta(bytes.NewReader(local), chunkSize, idx)
+	if err != nil {
+		t.Fatalf("ComputeDelta: %v", err)
+	}
+	if insertBytes != chunkSize {
+		t.Fatalf("expected exactly one changed chunk inserted (%d bytes), got %d", chunkSize, insertBytes)
+	}
+	var copies, inserts int
+	for _, op := range ops {
+		if op.Kind == OpCopy {
+			copies++
+		} else {
+			inserts++
+		}
+	}
+	if copies != 2 || inserts != 1 {
+		t.Fatalf("expected 2 copies + 1 insert, got %d copies + %d inserts", copies, inserts)
+	}
+}
+
+func TestComputeDeltaFindsShiftedChunksAfterPrependedByte(t *testing.T) {
+	chunkSize := int64(8)
+	remote := []byte("aaaaaaaabbbbbbbbcccccccc")
+	local := append([]byte("X"), remote...) // a single byte inserted at the front shifts every chunk by one
+	idx := BuildRemoteIndex(chunkSize, remoteHashesFor(t, chunkSize, remote))
+
+	ops, insertBytes, err := ComputeDelta(bytes.NewReader(local), chunkSize, idx)
+	if err != nil {
+		t.Fatalf("ComputeDelta: %v", err)
+	}
+	// Only the prepended byte should be new; everything else should be
+	// relocated as COPY ops even though it no longer sits on a
+	// chunkSize-aligned offset.
+	if insertBytes != 1 {
+		t.Fatalf("expected exactly the 1 prepended byte to be inserted, got %d", insertBytes)
+	}
+	var copies int
+	for _, op := range ops {
+		if op.Kind == OpCopy {
+			copies++
+		}
+	}
+	if copies != 3 {
+		t.Fatalf("expected all 3 remote chunks to be found shifted by one byte, got %d copy ops (%+v)", copies, ops)
+	}
+}
+
+func TestShouldFallbackToFullUpload(t *testing.T) {
+	if ShouldFallbackToFullUpload(69, 100) {
+		t.Fatal("69% changed should not trigger fallback")
+	}
+	if !ShouldFallbackToFullUpload(71, 100) {
+		t.Fatal("71% changed should trigger fallback")
+	}
+}
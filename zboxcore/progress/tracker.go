@@ -0,0 +1,106 @@
+// Package progress gives callers of ListRequest, CommitRequest and
+// UploadRequest a typed stream of what is happening per blobber, instead
+// of only the single final result those requests used to return. CLI/GUI
+// clients use it to render per-blobber progress bars and to tell a slow
+// blobber apart from a failed one.
+package progress
+
+import "time"
+
+// EventType identifies which field of Event is populated.
+type EventType int
+
+const (
+	// BlobberStarted fires when a request to BlobberID is issued.
+	BlobberStarted EventType = iota
+	// BlobberSucceeded fires when BlobberID's request completes; Bytes
+	// and Duration describe that attempt.
+	BlobberSucceeded
+	// BlobberFailed fires when BlobberID's request fails permanently
+	// (after any retries); Err is the final error.
+	BlobberFailed
+	// ConsensusReached fires once enough blobbers have agreed for the
+	// caller to act on the result; Consensus is the agreeing count.
+	ConsensusReached
+	// Retrying fires before a backoff sleep ahead of retry number
+	// Attempt against BlobberID.
+	Retrying
+)
+
+// Event is a single progress update. Only the fields relevant to Type are
+// populated.
+type Event struct {
+	Type      EventType
+	BlobberID string
+	Bytes     int64
+	Duration  time.Duration
+	Err       error
+	Consensus int
+	Attempt   int
+	Backoff   time.Duration
+}
+
+// Tracker delivers Events on a buffered channel. Callers that don't want
+// progress events simply pass a nil *Tracker; every emit method is a no-op
+// on a nil receiver.
+type Tracker struct {
+	events chan Event
+}
+
+// NewTracker creates a Tracker whose Events channel buffers up to
+// bufferSize events before emit calls start blocking.
+func NewTracker(bufferSize int) *Tracker {
+	return &Tracker{events: make(chan Event, bufferSize)}
+}
+
+// Events returns the channel progress events are delivered on. It is
+// closed by Close.
+func (t *Tracker) Events() <-chan Event {
+	if t == nil {
+		return nil
+	}
+	return t.events
+}
+
+// Close closes the underlying channel; callers must not emit after Close.
+func (t *Tracker) Close() {
+	if t == nil {
+		return
+	}
+	close(t.events)
+}
+
+func (t *Tracker) emit(ev Event) {
+	if t == nil {
+		return
+	}
+	t.events <- ev
+}
+
+// Started reports that a request to blobberID was issued.
+func (t *Tracker) Started(blobberID string) {
+	t.emit(Event{Type: BlobberStarted, BlobberID: blobberID})
+}
+
+// Succeeded reports that blobberID's request completed, having sent bytes
+// over dur.
+func (t *Tracker) Succeeded(blobberID string, bytes int64, dur time.Duration) {
+	t.emit(Event{Type: BlobberSucceeded, BlobberID: blobberID, Bytes: bytes, Duration: dur})
+}
+
+// Failed reports that blobberID's request failed with err after any
+// retries were exhausted.
+func (t *Tracker) Failed(blobberID string, err error) {
+	t.emit(Event{Type: BlobberFailed, BlobberID: blobberID, Err: err})
+}
+
+// Consensus reports that count blobbers have now agreed on a result.
+func (t *Tracker) Consensus(count int) {
+	t.emit(Event{Type: ConsensusReached, Consensus: count})
+}
+
+// Retrying reports that blobberID's request is about to be retried as
+// attempt, after sleeping backoff.
+func (t *Tracker) Retrying(blobberID string, attempt int, backoff time.Duration) {
+	t.emit(Event{Type: Retrying, BlobberID: blobberID, Attempt: attempt, Backoff: backoff})
+}
@@ -0,0 +1,40 @@
+package progress
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackerDeliversEventsInOrder(t *testing.T) {
+	tr := NewTracker(8)
+	tr.Started("blobber1")
+	tr.Retrying("blobber1", 1, time.Millisecond)
+	tr.Succeeded("blobber1", 42, time.Second)
+	tr.Consensus(3)
+	tr.Close()
+
+	var got []EventType
+	for ev := range tr.Events() {
+		got = append(got, ev.Type)
+	}
+	want := []EventType{BlobberStarted, Retrying, BlobberSucceeded, ConsensusReached}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNilTrackerIsANoOp(t *testing.T) {
+	var tr *Tracker
+	tr.Started("blobber1")
+	tr.Failed("blobber1", errors.New("boom"))
+	tr.Consensus(1)
+	if tr.Events() != nil {
+		t.Fatal("expected nil channel from a nil tracker")
+	}
+}
@@ -20,6 +20,9 @@ import (
 	"github.com/0chain/gosdk/zboxcore/fileref"
 	. "github.com/0chain/gosdk/zboxcore/logger"
 	"github.com/0chain/gosdk/zboxcore/marker"
+	"github.com/0chain/gosdk/zboxcore/progress"
+	"github.com/0chain/gosdk/zboxcore/transfer"
+	"github.com/0chain/gosdk/zboxcore/wmchain"
 	"github.com/0chain/gosdk/zboxcore/zboxutil"
 )
 
@@ -52,51 +55,87 @@ type CommitRequest struct {
 	result       *CommitResult
 }
 
-var commitChan map[string]chan *CommitRequest
+// commitBackoff governs retries of the blobber HTTP calls (reference path
+// fetch and commit) that processCommit makes through transferMgr.
+var commitBackoff = transfer.Backoff{Base: 500 * time.Millisecond, Max: 30 * time.Second, Retries: 4}
+
+// transferMgr dedupes concurrent commits that touch the same
+// (blobberID, allocationID, connectionID, path) and applies commitBackoff to
+// retry retryable failures, replacing the old one-slot-per-blobber channel.
+// Upload requests are not submitted to it.
+var transferMgr *transfer.Manager
 var initCommitMutex sync.Mutex
 
+// wmChains is the local record of every WriteMarker this client has issued
+// per allocation, used to catch a blobber reporting a stale LatestWM
+// instead of rolling forward honestly. A LatestWM reported by a blobber is
+// checked against it but not recorded in it.
+var wmChains *wmchain.Store
+
 func InitCommitWorker(blobbers []*blockchain.StorageNode) {
-	// if commitChan != nil {
-	// 	for _, v := range commitChan {
-	// 		close(v)
-	// 	}
-	// }
-	// commitChan = make(map[string]chan *CommitRequest)
-	// for _, blobber := range blobbers {
-	// 	Logger.Info("Atempting to start the commit worker for ", blobber.Baseurl)
-	// 	commitChan[blobber.ID] = make(chan *CommitRequest, 1)
-	// 	go startCommitWorker(blobber)
-	// }
 	initCommitMutex.Lock()
 	defer initCommitMutex.Unlock()
-	if commitChan == nil {
-		commitChan = make(map[string]chan *CommitRequest)
+	if transferMgr == nil {
+		transferMgr = transfer.NewManager(1, commitBackoff)
 	}
-
-	for _, blobber := range blobbers {
-		if _, ok := commitChan[blobber.ID]; !ok {
-			commitChan[blobber.ID] = make(chan *CommitRequest, 1)
-			blobberChan := commitChan[blobber.ID]
-			go startCommitWorker(blobberChan, blobber.ID)
+	if wmChains == nil {
+		baseDir, err := wmchain.DefaultBaseDir()
+		if err != nil {
+			Logger.Error("wmchain: could not resolve base dir, rollback detection disabled: ", err)
+			return
 		}
+		wmChains = wmchain.NewStore(baseDir)
 	}
+}
 
+// blobberHTTPError carries the HTTP status (0 for network-level failures)
+// so isRetryableCommitError can decide whether a retry is worthwhile.
+type blobberHTTPError struct {
+	status int
+	err    error
 }
 
-func startCommitWorker(blobberChan chan *CommitRequest, blobberID string) {
-	for true {
-		commitreq, open := <-blobberChan
-		if !open {
-			break
-		}
-		commitreq.processCommit()
+func (e *blobberHTTPError) Error() string { return e.err.Error() }
+func (e *blobberHTTPError) Unwrap() error { return e.err }
+
+func isRetryableCommitError(err error) bool {
+	herr, ok := err.(*blobberHTTPError)
+	if !ok {
+		return false
+	}
+	return herr.status == 0 || herr.status >= http.StatusInternalServerError
+}
+
+func (commitreq *CommitRequest) commitKey() transfer.Key {
+	path := ""
+	for _, change := range commitreq.changes {
+		path = change.GetAffectedPath()
+	}
+	return transfer.Key{
+		BlobberID:    commitreq.blobber.ID,
+		AllocationID: commitreq.allocationID,
+		ConnectionID: commitreq.connectionID,
+		Path:         path,
 	}
-	initCommitMutex.Lock()
-	defer initCommitMutex.Unlock()
-	delete(commitChan, blobberID)
 }
 
-func (commitreq *CommitRequest) processCommit() {
+// checkNotRollback compares a blobber-reported LatestWM against this
+// client's local wmchain tip for that blobber, returning a
+// *wmchain.RollbackDetectedError if the blobber is presenting an older
+// marker than the one this client last recorded for it.
+func (commitreq *CommitRequest) checkNotRollback(latestWM *marker.WriteMarker) error {
+	if wmChains == nil {
+		return nil
+	}
+	chain, err := wmChains.Chain(commitreq.allocationID)
+	if err != nil {
+		Logger.Error("wmchain: could not open chain, skipping rollback check: ", err)
+		return nil
+	}
+	return chain.CheckNotRollback(commitreq.blobber.ID, latestWM)
+}
+
+func (commitreq *CommitRequest) processCommit(ctx context.Context) error {
 	Logger.Info("received a commit request")
 	path := ""
 	for _, change := range commitreq.changes {
@@ -107,13 +146,13 @@ func (commitreq *CommitRequest) processCommit() {
 	req, err := zboxutil.NewReferencePathRequest(commitreq.blobber.Baseurl, commitreq.allocationID, path)
 	if err != nil || len(path) == 0 {
 		Logger.Error("Creating ref path req", err)
-		return
+		return err
 	}
-	ctx, cncl := context.WithTimeout(context.Background(), (time.Second * 30))
-	err = zboxutil.HttpDo(ctx, cncl, req, func(resp *http.Response, err error) error {
+	rctx, rcncl := context.WithTimeout(ctx, (time.Second * 30))
+	err = zboxutil.HttpDo(rctx, rcncl, req, func(resp *http.Response, err error) error {
 		if err != nil {
 			Logger.Error("List:", err)
-			return err
+			return &blobberHTTPError{err: err}
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode != http.StatusOK {
@@ -122,47 +161,42 @@ func (commitreq *CommitRequest) processCommit() {
 		resp_body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			Logger.Error("List: Resp", err)
-			return err
+			return &blobberHTTPError{status: resp.StatusCode, err: err}
 		}
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("List error response: Status: %d - %s ", resp.StatusCode, string(resp_body))
+			return &blobberHTTPError{status: resp.StatusCode, err: fmt.Errorf("List error response: Status: %d - %s ", resp.StatusCode, string(resp_body))}
 		} else {
 			Logger.Info("Reference path:", string(resp_body))
 			err = json.Unmarshal(resp_body, &lR)
 			if err != nil {
 				Logger.Error("List json decode error: ", err)
-				return err
+				return &blobberHTTPError{status: resp.StatusCode, err: err}
 			}
 		}
 		return nil
 	})
 	//process the commit request for the blobber here
 	if err != nil {
-		commitreq.result = ErrorCommitResult(err.Error())
-		commitreq.wg.Done()
-		return
+		return err
 	}
 	rootRef, err := lR.GetDirTree(commitreq.allocationID)
 	if lR.LatestWM != nil {
 		//TODO: Verify the writemarker
 		err = lR.LatestWM.VerifySignature(client.GetClientPublicKey())
 		if err != nil {
-			commitreq.result = ErrorCommitResult(err.Error())
-			commitreq.wg.Done()
-			return
+			return err
 		}
 		rootRef.CalculateHash()
 		prevAllocationRoot := encryption.Hash(rootRef.Hash + ":" + strconv.FormatInt(lR.LatestWM.Timestamp, 10))
 		if prevAllocationRoot != lR.LatestWM.AllocationRoot {
-			commitreq.result = ErrorCommitResult("Allocation root from latest writemarker mismatch")
-			commitreq.wg.Done()
-			return
+			return common.NewError("allocation_root_mismatch", "Allocation root from latest writemarker mismatch")
+		}
+		if err := commitreq.checkNotRollback(lR.LatestWM); err != nil {
+			return err
 		}
 	}
 	if err != nil {
-		commitreq.result = ErrorCommitResult(err.Error())
-		commitreq.wg.Done()
-		return
+		return err
 	}
 	size := int64(0)
 	for _, change := range commitreq.changes {
@@ -173,21 +207,12 @@ func (commitreq *CommitRequest) processCommit() {
 		size += change.GetSize()
 	}
 	if err != nil {
-		commitreq.result = ErrorCommitResult(err.Error())
-		commitreq.wg.Done()
-		return
-	}
-	err = commitreq.commitBlobber(rootRef, lR.LatestWM, size)
-	if err != nil {
-		commitreq.result = ErrorCommitResult(err.Error())
-		commitreq.wg.Done()
-		return
+		return err
 	}
-	commitreq.result = SuccessCommitResult()
-	commitreq.wg.Done()
+	return commitreq.commitBlobber(ctx, rootRef, lR.LatestWM, size)
 }
 
-func (req *CommitRequest) commitBlobber(rootRef *fileref.Ref, latestWM *marker.WriteMarker, size int64) error {
+func (req *CommitRequest) commitBlobber(ctx context.Context, rootRef *fileref.Ref, latestWM *marker.WriteMarker, size int64) error {
 	wm := &marker.WriteMarker{}
 	timestamp := common.Now()
 	wm.AllocationRoot = encryption.Hash(rootRef.Hash + ":" + strconv.FormatInt(timestamp, 10))
@@ -224,11 +249,11 @@ func (req *CommitRequest) commitBlobber(rootRef *fileref.Ref, latestWM *marker.W
 		return err
 	}
 	httpreq.Header.Add("Content-Type", formWriter.FormDataContentType())
-	ctx, cncl := context.WithTimeout(context.Background(), (time.Second * 60))
-	err = zboxutil.HttpDo(ctx, cncl, httpreq, func(resp *http.Response, err error) error {
+	cctx, cncl := context.WithTimeout(ctx, (time.Second * 60))
+	err = zboxutil.HttpDo(cctx, cncl, httpreq, func(resp *http.Response, err error) error {
 		if err != nil {
 			Logger.Error("Commit: ", err)
-			return err
+			return &blobberHTTPError{err: err}
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode == http.StatusOK {
@@ -240,17 +265,68 @@ func (req *CommitRequest) commitBlobber(rootRef *fileref.Ref, latestWM *marker.W
 		resp_body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			Logger.Error("Response read: ", err)
-			return err
+			return &blobberHTTPError{status: resp.StatusCode, err: err}
 		}
 		if resp.StatusCode != http.StatusOK {
 			Logger.Error(req.blobber.Baseurl, " Commit response:", string(resp_body))
-			return common.NewError("commit_error", string(resp_body))
+			return &blobberHTTPError{status: resp.StatusCode, err: common.NewError("commit_error", string(resp_body))}
 		}
 		return nil
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	if wmChains != nil {
+		if chain, cerr := wmChains.Chain(req.allocationID); cerr == nil {
+			if aerr := chain.Append(req.blobber.ID, wm); aerr != nil {
+				Logger.Error("wmchain: failed to append new write marker: ", aerr)
+			}
+		}
+	}
+	return nil
 }
 
+// AddCommitRequest submits req to the transfer manager with a background
+// context and no progress tracker; it is a thin wrapper kept for existing
+// callers. New code that wants cancellation or progress events should call
+// AddCommitRequestCtx directly.
 func AddCommitRequest(req *CommitRequest) {
-	commitChan[req.blobber.ID] <- req
-}
\ No newline at end of file
+	AddCommitRequestCtx(context.Background(), nil, req)
+}
+
+// AddCommitRequestCtx submits req to the transfer manager, which dedupes
+// it against any other in-flight commit touching the same allocation,
+// connection and path, retries retryable blobber failures with backoff,
+// and reports the outcome through req.result once req.wg.Done fires.
+// Cancelling ctx releases this caller's interest in the transfer: if no
+// other caller is watching it, the outstanding HTTP calls backing it are
+// cancelled and the commit fails; while another caller still watches it,
+// this caller keeps waiting for the shared result. tracker, if non-nil,
+// receives per-blobber progress events.
+func AddCommitRequestCtx(ctx context.Context, tracker *progress.Tracker, req *CommitRequest) {
+	blobberID := req.blobber.ID
+	tracker.Started(blobberID)
+	w := transferMgr.Watch(ctx, blobberID, req.commitKey(), isRetryableCommitError, func(ctx context.Context, prog func(int64)) error {
+		return req.processCommit(ctx)
+	})
+	go func() {
+		defer w.Release()
+		start := time.Now()
+		for ev := range w.Events() {
+			switch ev.Type {
+			case transfer.EventRetrying:
+				tracker.Retrying(blobberID, ev.Attempt, ev.Backoff)
+			case transfer.EventFailed:
+				tracker.Failed(blobberID, ev.Err)
+				req.result = ErrorCommitResult(ev.Err.Error())
+				req.wg.Done()
+				return
+			case transfer.EventSucceeded:
+				tracker.Succeeded(blobberID, 0, time.Since(start))
+				req.result = SuccessCommitResult()
+				req.wg.Done()
+				return
+			}
+		}
+	}()
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/0chain/gosdk/zboxcore/fileref"
 	. "github.com/0chain/gosdk/zboxcore/logger"
 	"github.com/0chain/gosdk/zboxcore/marker"
+	"github.com/0chain/gosdk/zboxcore/progress"
 	"github.com/0chain/gosdk/zboxcore/zboxutil"
 )
 
@@ -50,7 +51,7 @@ type ListResult struct {
 	Consensus `json:"-"`
 }
 
-func (req *ListRequest) getListInfoFromBlobber(blobber *blockchain.StorageNode, blobberIdx int, rspCh chan<- *listResponse) {
+func (req *ListRequest) getListInfoFromBlobber(ctx context.Context, tracker *progress.Tracker, blobber *blockchain.StorageNode, blobberIdx int, rspCh chan<- *listResponse) {
 	defer req.wg.Done()
 	body := new(bytes.Buffer)
 	formWriter := multipart.NewWriter(body)
@@ -58,10 +59,17 @@ func (req *ListRequest) getListInfoFromBlobber(blobber *blockchain.StorageNode,
 	ref := &fileref.Ref{}
 	var s strings.Builder
 	var err error
+	start := time.Now()
 	listRetFn := func() {
+		if err != nil {
+			tracker.Failed(blobber.ID, err)
+		} else {
+			tracker.Succeeded(blobber.ID, int64(len(s.String())), time.Since(start))
+		}
 		rspCh <- &listResponse{ref: ref, responseStr: s.String(), blobberIdx: blobberIdx, err: err}
 	}
 	defer listRetFn()
+	tracker.Started(blobber.ID)
 
 	formWriter.WriteField("path", req.remotefilepath)
 
@@ -73,8 +81,8 @@ func (req *ListRequest) getListInfoFromBlobber(blobber *blockchain.StorageNode,
 	}
 
 	httpreq.Header.Add("Content-Type", formWriter.FormDataContentType())
-	ctx, cncl := context.WithTimeout(req.ctx, (time.Second * 30))
-	err = zboxutil.HttpDo(ctx, cncl, httpreq, func(resp *http.Response, err error) error {
+	hctx, cncl := context.WithTimeout(ctx, (time.Second * 30))
+	err = zboxutil.HttpDo(hctx, cncl, httpreq, func(resp *http.Response, err error) error {
 		if err != nil {
 			Logger.Error("List : ", err)
 			return err
@@ -103,13 +111,13 @@ func (req *ListRequest) getListInfoFromBlobber(blobber *blockchain.StorageNode,
 	})
 }
 
-func (req *ListRequest) getlistFromBlobbers() []*listResponse {
+func (req *ListRequest) getlistFromBlobbers(ctx context.Context, tracker *progress.Tracker) []*listResponse {
 	numList := len(req.blobbers)
 	req.wg = &sync.WaitGroup{}
 	req.wg.Add(numList)
 	rspCh := make(chan *listResponse, numList)
 	for i := 0; i < numList; i++ {
-		go req.getListInfoFromBlobber(req.blobbers[i], i, rspCh)
+		go req.getListInfoFromBlobber(ctx, tracker, req.blobbers[i], i, rspCh)
 	}
 	req.wg.Wait()
 	listInfos := make([]*listResponse, len(req.blobbers))
@@ -119,8 +127,21 @@ func (req *ListRequest) getlistFromBlobbers() []*listResponse {
 	return listInfos
 }
 
+// GetListFromBlobbers fetches req.remotefilepath from every blobber and
+// reconciles their answers into a consensus ListResult. It is a thin
+// wrapper around GetListFromBlobbersCtx using req.ctx and no progress
+// tracker, kept for existing callers.
 func (req *ListRequest) GetListFromBlobbers() *ListResult {
-	lR := req.getlistFromBlobbers()
+	return req.GetListFromBlobbersCtx(req.ctx, nil)
+}
+
+// GetListFromBlobbersCtx is GetListFromBlobbers with an explicit ctx (whose
+// cancellation aborts the outstanding per-blobber HTTP calls) and an
+// optional progress.Tracker that receives a BlobberStarted/Succeeded/Failed
+// event per blobber and, once the result is final, a ConsensusReached event
+// carrying the number of blobbers that returned a listing.
+func (req *ListRequest) GetListFromBlobbersCtx(ctx context.Context, tracker *progress.Tracker) *ListResult {
+	lR := req.getlistFromBlobbers(ctx, tracker)
 	var result *ListResult
 	result = &ListResult{}
 	selected := make(map[string]*ListResult)
@@ -172,5 +193,12 @@ func (req *ListRequest) GetListFromBlobbers() *ListResult {
 			result.NumBlocks += child.NumBlocks
 		}
 	}
+	listed := 0
+	for _, ti := range lR {
+		if ti.err == nil && ti.ref != nil {
+			listed++
+		}
+	}
+	tracker.Consensus(listed)
 	return result
-}
\ No newline at end of file
+}
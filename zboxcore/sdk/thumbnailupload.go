@@ -2,18 +2,34 @@ package sdk
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"io"
 	"math"
 	"math/bits"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/0chain/gosdk/zboxcore/encoder"
 	"github.com/0chain/gosdk/zboxcore/fileref"
 	. "github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/progress"
 )
 
+// thumbnailTargets returns the IDs of the blobbers req.uploadMask still
+// selects, in the same order pushThumbnailData/completeThumbnailPush walk
+// the mask to write to req.uploadThumbCh.
+func (req *UploadRequest) thumbnailTargets() []string {
+	var ids []string
+	pos := 0
+	for i := req.uploadMask; i != 0; i &= ^(1 << uint32(pos)) {
+		pos = bits.TrailingZeros32(i)
+		ids = append(ids, req.blobbers[pos].ID)
+	}
+	return ids
+}
+
 func (req *UploadRequest) pushThumbnailData(data []byte) error {
 	//TODO: Check for optimization
 	n := int64(math.Min(float64(req.thumbRemaining), float64(len(data))))
@@ -39,11 +55,46 @@ func (req *UploadRequest) pushThumbnailData(data []byte) error {
 	return nil
 }
 
+// processThumbnail pushes the thumbnail with a background context and no
+// progress tracker; it is a thin wrapper kept for existing callers outside
+// this tree slice. New code that wants cancellation or progress events
+// should call processThumbnailCtx directly.
 func (req *UploadRequest) processThumbnail(a *Allocation, wg *sync.WaitGroup) {
+	req.processThumbnailCtx(context.Background(), nil, a, wg)
+}
+
+// processThumbnailCtx pushes the thumbnail in CHUNK_SIZE-aligned shards,
+// checking ctx between chunks so a cancelled upload stops pushing shards
+// promptly instead of running to completion first.
+//
+// Scope note: this only gives processThumbnailCtx cooperative cancellation.
+// The actual per-blobber HTTP calls for a thumbnail (and file) upload are
+// made by the workers reading uploadThumbCh/uploadCh, which live outside
+// this file and are not part of this tree slice, so porting them onto
+// transferMgr for retry/backoff/dedup parity with commits has to happen
+// there, not here.
+//
+// tracker, if non-nil, receives a BlobberStarted event per targeted
+// blobber up front and a BlobberSucceeded/BlobberFailed event per blobber
+// once the push finishes, completing the ListRequest/CommitRequest
+// progress coverage with UploadRequest's own thumbnail path.
+func (req *UploadRequest) processThumbnailCtx(ctx context.Context, tracker *progress.Tracker, a *Allocation, wg *sync.WaitGroup) {
 	defer wg.Done()
+	start := time.Now()
+	targets := req.thumbnailTargets()
+	for _, id := range targets {
+		tracker.Started(id)
+	}
+	fail := func(err error) {
+		for _, id := range targets {
+			tracker.Failed(id, err)
+		}
+	}
+
 	var inFile *os.File
 	inFile, err := os.Open(req.thumbnailpath)
 	if err != nil {
+		fail(err)
 		return
 	}
 	size := req.filemeta.ThumbnailSize
@@ -57,22 +108,36 @@ func (req *UploadRequest) processThumbnail(a *Allocation, wg *sync.WaitGroup) {
 
 	sent := int(0)
 	for ctr := int64(0); ctr < chunksPerShard; ctr++ {
+		select {
+		case <-ctx.Done():
+			Logger.Info("Thumbnail upload cancelled: ", ctx.Err())
+			fail(ctx.Err())
+			return
+		default:
+		}
 		remaining := int64(math.Min(float64(perShard-(ctr*fileref.CHUNK_SIZE)), fileref.CHUNK_SIZE))
 		b1 := make([]byte, remaining*int64(a.DataShards))
 		_, err = dataReader.Read(b1)
 		if err != nil {
+			fail(err)
 			return
 		}
 		err = req.pushThumbnailData(b1)
 		if err != nil {
+			fail(err)
 			return
 		}
 		sent = sent + int(remaining*int64(a.DataShards+a.ParityShards))
 	}
 	err = req.completeThumbnailPush()
 	if err != nil {
+		fail(err)
 		return
 	}
+	for _, id := range targets {
+		// sent covers every shard; each blobber received one shard's share.
+		tracker.Succeeded(id, int64(sent)/int64(a.DataShards+a.ParityShards), time.Since(start))
+	}
 }
 
 func (req *UploadRequest) completeThumbnailPush() error {
@@ -86,4 +151,4 @@ func (req *UploadRequest) completeThumbnailPush() error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
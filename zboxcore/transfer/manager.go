@@ -0,0 +1,282 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Key identifies a logical transfer. Concurrent callers that request the
+// same Key share a single in-flight Transfer instead of each doing the
+// work independently. BlobberID must be part of the Key: a commit (or
+// upload) fans out one call per blobber against the same allocation,
+// connection and path, each holding a different erasure-coded shard, so
+// those calls must never dedupe against each other.
+type Key struct {
+	BlobberID    string
+	AllocationID string
+	ConnectionID string
+	Path         string
+}
+
+// EventType enumerates the kinds of progress updates a Watcher receives.
+type EventType int
+
+const (
+	EventStarted EventType = iota
+	EventProgress
+	EventRetrying
+	EventSucceeded
+	EventFailed
+)
+
+// Event is a single progress update delivered to a Watcher's channel.
+type Event struct {
+	Type    EventType
+	Bytes   int64
+	Attempt int
+	Backoff time.Duration
+	Err     error
+}
+
+// Func performs the actual transfer work for one attempt. Implementations
+// must respect ctx cancellation and may call progress to report bytes
+// sent so far.
+type Func func(ctx context.Context, progress func(bytes int64)) error
+
+// Retryable classifies an error returned by a Func as worth retrying
+// (5xx responses, network errors) or permanent.
+type Retryable func(err error) bool
+
+// Backoff configures the exponential retry schedule used by the Manager.
+type Backoff struct {
+	Base    time.Duration
+	Max     time.Duration
+	Retries int
+}
+
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Base << uint(attempt)
+	if d <= 0 || d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// transfer is the state shared by every Watcher racing on the same Key.
+type transfer struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	watchers int
+	subs     []chan Event
+	finished bool
+	result   Event // set under mu when finished is set; the terminal event
+}
+
+// subscribe returns a channel of events for this transfer. A caller that
+// subscribes after the transfer has already finished (the Manager's
+// inflight map can still point at a finished-but-not-yet-forgotten
+// transfer - see Manager.run/forget) gets the terminal event delivered
+// immediately instead of a channel nothing will ever publish to.
+func (t *transfer) subscribe() chan Event {
+	ch := make(chan Event, 8)
+	t.mu.Lock()
+	if t.finished {
+		ev := t.result
+		t.mu.Unlock()
+		ch <- ev
+		close(ch)
+		return ch
+	}
+	t.watchers++
+	t.subs = append(t.subs, ch)
+	t.mu.Unlock()
+	return ch
+}
+
+func (t *transfer) publish(ev Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return
+	}
+	for _, ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (t *transfer) finish(err error) {
+	t.mu.Lock()
+	if t.finished {
+		t.mu.Unlock()
+		return
+	}
+	ev := Event{Type: EventSucceeded}
+	if err != nil {
+		ev = Event{Type: EventFailed, Err: err}
+	}
+	t.finished = true
+	t.result = ev
+	subs := t.subs
+	t.mu.Unlock()
+
+	for _, ch := range subs {
+		ch <- ev
+		close(ch)
+	}
+}
+
+// Watcher observes one Transfer. Callers must call Release once they are
+// done watching; the underlying Transfer is only cancelled once every
+// Watcher that joined it has released.
+type Watcher struct {
+	events chan Event
+	t      *transfer
+	m      *Manager
+	key    Key
+	once   sync.Once
+}
+
+// Events returns the channel of progress events for this Transfer. The
+// channel is closed once the transfer reaches EventSucceeded/EventFailed.
+func (w *Watcher) Events() <-chan Event { return w.events }
+
+// Release drops this Watcher's interest in the Transfer. Once the last
+// Watcher releases, the Transfer's context is cancelled. Safe to call more
+// than once (e.g. once explicitly and once via the watch ctx cancelling).
+func (w *Watcher) Release() {
+	w.once.Do(func() { w.m.release(w.key, w.t) })
+}
+
+// Manager deduplicates concurrent transfers that share a Key, bounds
+// per-blobber concurrency, and retries failed attempts with exponential
+// backoff. It is modeled on the shared-download-manager pattern: multiple
+// callers asking for the same object join one in-flight operation and
+// split its result rather than each repeating the work.
+type Manager struct {
+	mu         sync.Mutex
+	inflight   map[Key]*transfer
+	slots      map[string]chan struct{}
+	perBlobber int
+	backoff    Backoff
+}
+
+// NewManager creates a Manager that allows perBlobberConcurrency concurrent
+// transfers per blobber ID and retries failed attempts per backoff.
+func NewManager(perBlobberConcurrency int, backoff Backoff) *Manager {
+	if perBlobberConcurrency <= 0 {
+		perBlobberConcurrency = 1
+	}
+	return &Manager{
+		inflight:   make(map[Key]*transfer),
+		slots:      make(map[string]chan struct{}),
+		perBlobber: perBlobberConcurrency,
+		backoff:    backoff,
+	}
+}
+
+func (m *Manager) slot(blobberID string) chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.slots[blobberID]
+	if !ok {
+		s = make(chan struct{}, m.perBlobber)
+		m.slots[blobberID] = s
+	}
+	return s
+}
+
+// Watch joins (or starts) the Transfer for key and returns a Watcher for
+// it. If a Transfer for key is already in flight, fn is not called again;
+// the new Watcher simply shares the existing attempt's result. ctx is this
+// caller's own cancellation: if it is done before the transfer finishes,
+// this Watcher releases automatically (as if the caller had called
+// Release itself), without affecting any other Watcher still attached to
+// the same Transfer.
+func (m *Manager) Watch(ctx context.Context, blobberID string, key Key, retryable Retryable, fn Func) *Watcher {
+	m.mu.Lock()
+	t, ok := m.inflight[key]
+	if !ok {
+		tctx, cancel := context.WithCancel(context.Background())
+		t = &transfer{ctx: tctx, cancel: cancel}
+		m.inflight[key] = t
+		m.mu.Unlock()
+		go m.run(t, blobberID, key, retryable, fn)
+	} else {
+		m.mu.Unlock()
+	}
+	w := &Watcher{events: t.subscribe(), t: t, m: m, key: key}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Release()
+		case <-t.ctx.Done():
+		}
+	}()
+	return w
+}
+
+func (m *Manager) release(key Key, t *transfer) {
+	t.mu.Lock()
+	// A Watcher whose subscribe() landed after finish() (see subscribe)
+	// was never counted here, so don't let its Release drive the count
+	// negative and mis-trigger an early cancel for a still-watched transfer.
+	if t.watchers > 0 {
+		t.watchers--
+	}
+	remaining := t.watchers
+	t.mu.Unlock()
+	if remaining <= 0 {
+		t.cancel()
+	}
+}
+
+func (m *Manager) run(t *transfer, blobberID string, key Key, retryable Retryable, fn Func) {
+	slot := m.slot(blobberID)
+	slot <- struct{}{}
+	defer func() { <-slot }()
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		t.publish(Event{Type: EventStarted, Attempt: attempt})
+		err = fn(t.ctx, func(bytes int64) {
+			t.publish(Event{Type: EventProgress, Bytes: bytes})
+		})
+		if err == nil {
+			break
+		}
+		if t.ctx.Err() != nil {
+			err = t.ctx.Err()
+			break
+		}
+		if attempt >= m.backoff.Retries || retryable == nil || !retryable(err) {
+			break
+		}
+		d := m.backoff.delay(attempt)
+		t.publish(Event{Type: EventRetrying, Attempt: attempt + 1, Backoff: d})
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-t.ctx.Done():
+			timer.Stop()
+			err = t.ctx.Err()
+			t.finish(err)
+			m.forget(key, t)
+			return
+		}
+	}
+	t.finish(err)
+	m.forget(key, t)
+}
+
+func (m *Manager) forget(key Key, t *transfer) {
+	m.mu.Lock()
+	if m.inflight[key] == t {
+		delete(m.inflight, key)
+	}
+	m.mu.Unlock()
+}
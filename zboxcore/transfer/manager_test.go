@@ -0,0 +1,170 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func alwaysRetryable(err error) bool { return err != nil }
+
+func TestWatchDeduplicatesConcurrentCallers(t *testing.T) {
+	m := NewManager(4, Backoff{Base: time.Millisecond, Max: time.Millisecond, Retries: 0})
+	key := Key{BlobberID: "blobber1", AllocationID: "a1", ConnectionID: "c1", Path: "/x"}
+
+	var calls int32
+	fn := func(ctx context.Context, progress func(int64)) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := m.Watch(context.Background(), "blobber1", key, alwaysRetryable, fn)
+			defer w.Release()
+			for ev := range w.Events() {
+				if ev.Type == EventSucceeded || ev.Type == EventFailed {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected fn to run once for deduplicated transfer, ran %d times", got)
+	}
+}
+
+func TestReleaseOnlyCancelsAfterAllWatchersRelease(t *testing.T) {
+	m := NewManager(1, Backoff{Base: time.Millisecond, Max: time.Millisecond, Retries: 0})
+	key := Key{BlobberID: "blobber1", AllocationID: "a1", ConnectionID: "c1", Path: "/x"}
+
+	started := make(chan struct{})
+	fn := func(ctx context.Context, progress func(int64)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	w1 := m.Watch(context.Background(), "blobber1", key, alwaysRetryable, fn)
+	w2 := m.Watch(context.Background(), "blobber1", key, alwaysRetryable, fn)
+	<-started
+	// run publishes EventStarted before calling fn, so once started is
+	// closed it is already buffered for w2 if w2 subscribed in time; drain
+	// it without blocking, since w2 may have subscribed after the publish.
+	select {
+	case <-w2.Events():
+	default:
+	}
+
+	w1.Release()
+	select {
+	case ev := <-w2.Events():
+		t.Fatalf("transfer cancelled while a watcher was still attached: %+v", ev)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	w2.Release()
+	select {
+	case ev, ok := <-w2.Events():
+		if ok && ev.Type != EventFailed {
+			t.Fatalf("expected EventFailed after last watcher released, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for cancellation after last release")
+	}
+}
+
+func TestWatchDoesNotDedupeAcrossBlobbers(t *testing.T) {
+	m := NewManager(4, Backoff{Base: time.Millisecond, Max: time.Millisecond, Retries: 0})
+
+	var calls int32
+	fn := func(ctx context.Context, progress func(int64)) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}
+
+	blobberIDs := []string{"blobberA", "blobberB"}
+	var wg sync.WaitGroup
+	for _, id := range blobberIDs {
+		id := id
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := Key{BlobberID: id, AllocationID: "a1", ConnectionID: "c1", Path: "/x"}
+			w := m.Watch(context.Background(), id, key, alwaysRetryable, fn)
+			defer w.Release()
+			for ev := range w.Events() {
+				if ev.Type == EventSucceeded || ev.Type == EventFailed {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != int32(len(blobberIDs)) {
+		t.Fatalf("expected fn to run once per blobber (%d calls), ran %d times", len(blobberIDs), got)
+	}
+}
+
+func TestLateSubscribeAfterFinishGetsTerminalEvent(t *testing.T) {
+	// Reproduces the gap between a transfer finishing and the Manager
+	// forgetting its Key: a Watch() call that lands in that window finds
+	// the (still-registered) finished transfer and subscribes to it.
+	// subscribe() must hand back the terminal event immediately instead of
+	// a channel nothing will ever publish to, or this hangs forever.
+	tr := &transfer{ctx: context.Background()}
+	tr.finish(nil)
+
+	ch := tr.subscribe()
+	select {
+	case ev, ok := <-ch:
+		if !ok || ev.Type != EventSucceeded {
+			t.Fatalf("expected EventSucceeded, got %+v (ok=%v)", ev, ok)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late subscriber never received the terminal event")
+	}
+	if ev, ok := <-ch; ok {
+		t.Fatalf("expected channel to be closed after the terminal event, got %+v", ev)
+	}
+}
+
+func TestRetryThenGiveUp(t *testing.T) {
+	m := NewManager(1, Backoff{Base: time.Millisecond, Max: 5 * time.Millisecond, Retries: 2})
+	key := Key{BlobberID: "blobber1", AllocationID: "a1", ConnectionID: "c1", Path: "/x"}
+
+	var attempts int32
+	wantErr := errors.New("5xx from blobber")
+	fn := func(ctx context.Context, progress func(int64)) error {
+		atomic.AddInt32(&attempts, 1)
+		return wantErr
+	}
+
+	w := m.Watch(context.Background(), "blobber1", key, alwaysRetryable, fn)
+	defer w.Release()
+
+	var last Event
+	for ev := range w.Events() {
+		last = ev
+		if ev.Type == EventFailed || ev.Type == EventSucceeded {
+			break
+		}
+	}
+
+	if last.Type != EventFailed {
+		t.Fatalf("expected EventFailed, got %+v", last)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("expected 1 initial attempt + 2 retries = 3 calls, got %d", got)
+	}
+}
@@ -0,0 +1,369 @@
+// Package wmchain keeps a local, append-only record of every WriteMarker a
+// client has issued or observed per allocation, chained by
+// PreviousAllocationRoot. processCommit only ever sees the single LatestWM
+// a blobber chooses to report, which lets a blobber silently roll back to
+// any older marker it once validly signed; comparing that report against
+// the chain this package maintains catches that instead of trusting the
+// blobber's word for its own history.
+package wmchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/0chain/gosdk/zboxcore/marker"
+)
+
+// RollbackDetectedError is returned when a blobber's reported LatestWM
+// does not appear at-or-after the local chain's last known tip for that
+// blobber - i.e. the blobber is presenting an older, but still validly
+// signed, marker as its current one.
+type RollbackDetectedError struct {
+	BlobberID    string
+	KnownTipRoot string
+	ReportedRoot string
+}
+
+func (e *RollbackDetectedError) Error() string {
+	return fmt.Sprintf("rollback detected for blobber %s: local tip is %s but blobber reported %s",
+		e.BlobberID, e.KnownTipRoot, e.ReportedRoot)
+}
+
+// Entry is one link in the chain: a WriteMarker plus which blobber it was
+// issued to, since one allocation's chain spans every blobber it uses.
+type Entry struct {
+	BlobberID string              `json:"blobber_id"`
+	WM        *marker.WriteMarker `json:"write_marker"`
+}
+
+// indexRecord is a fixed-width entry in the on-disk fanout index: the
+// sha256 of an entry's AllocationRoot plus its position in chain.log, so a
+// lookup by AllocationRoot is a binary search rather than a linear scan of
+// the log (the layout mirrors a git commit-graph chunk: a 256-wide fanout
+// table over the first index byte, followed by sorted fixed-width rows).
+type indexRecord struct {
+	key    [sha256.Size]byte
+	offset uint64
+	length uint32
+}
+
+const indexRecordSize = sha256.Size + 8 + 4
+
+// Chain is the local WriteMarker history for one allocation.
+type Chain struct {
+	mu      sync.Mutex
+	logPath string
+	idxPath string
+	records []indexRecord // sorted by key, for O(log n) lookup by root
+	tips    map[string]*marker.WriteMarker
+}
+
+// Open loads (or creates) the chain for allocationID under baseDir,
+// replaying chain.log to rebuild the in-memory fanout index and per-blobber
+// tips.
+func Open(baseDir, allocationID string) (*Chain, error) {
+	dir := filepath.Join(baseDir, allocationID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &Chain{
+		logPath: filepath.Join(dir, "chain.log"),
+		idxPath: filepath.Join(dir, "chain.idx"),
+		tips:    make(map[string]*marker.WriteMarker),
+	}
+	if err := c.replay(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// replay reads every record in chain.log in order, rebuilding c.records and
+// c.tips. It is the source of truth; chain.idx is rewritten by writeIndex
+// after each Append and is not read back by Open.
+func (c *Chain) replay() error {
+	f, err := os.Open(c.logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset uint64
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(lenBuf[:])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		c.records = append(c.records, indexRecord{
+			key:    sha256.Sum256([]byte(entry.WM.AllocationRoot)),
+			offset: offset,
+			length: length,
+		})
+		c.tips[entry.BlobberID] = entry.WM
+		offset += 4 + uint64(length)
+	}
+	sort.Slice(c.records, func(i, j int) bool { return bytes.Compare(c.records[i].key[:], c.records[j].key[:]) < 0 })
+	return nil
+}
+
+// Tip returns the last WriteMarker this chain has recorded for blobberID.
+func (c *Chain) Tip(blobberID string) (*marker.WriteMarker, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wm, ok := c.tips[blobberID]
+	return wm, ok
+}
+
+// CheckNotRollback compares a blobber-reported LatestWM against the local
+// tip for that blobber. It only rejects a marker that is strictly older
+// than the known tip; a marker at or after the tip (including one another
+// device advanced past what this chain has recorded) passes.
+func (c *Chain) CheckNotRollback(blobberID string, reported *marker.WriteMarker) error {
+	tip, ok := c.Tip(blobberID)
+	if !ok || reported == nil {
+		return nil
+	}
+	if reported.AllocationRoot == tip.AllocationRoot {
+		return nil
+	}
+	if reported.Timestamp >= tip.Timestamp {
+		return nil
+	}
+	return &RollbackDetectedError{
+		BlobberID:    blobberID,
+		KnownTipRoot: tip.AllocationRoot,
+		ReportedRoot: reported.AllocationRoot,
+	}
+}
+
+// Append records wm as the new tip for blobberID and persists it to
+// chain.log, then refreshes the on-disk fanout index.
+func (c *Chain) Append(blobberID string, wm *marker.WriteMarker) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(Entry{BlobberID: blobberID, WM: wm})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(c.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	if _, err := f.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	c.records = append(c.records, indexRecord{
+		key:    sha256.Sum256([]byte(wm.AllocationRoot)),
+		offset: uint64(offset),
+		length: uint32(len(data)),
+	})
+	sort.Slice(c.records, func(i, j int) bool { return bytes.Compare(c.records[i].key[:], c.records[j].key[:]) < 0 })
+	c.tips[blobberID] = wm
+
+	return c.writeIndex()
+}
+
+// LookupByRoot finds the chain Entry for allocationRoot by a binary search
+// of the in-memory sorted index records, in O(log n) rather than scanning
+// the log. The fanout table is not consulted.
+func (c *Chain) LookupByRoot(allocationRoot string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sha256.Sum256([]byte(allocationRoot))
+	i := sort.Search(len(c.records), func(i int) bool {
+		return bytes.Compare(c.records[i].key[:], key[:]) >= 0
+	})
+	if i >= len(c.records) || c.records[i].key != key {
+		return nil, false
+	}
+	rec := c.records[i]
+
+	f, err := os.Open(c.logPath)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	data := make([]byte, rec.length)
+	if _, err := f.ReadAt(data, int64(rec.offset+4)); err != nil {
+		return nil, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// writeIndex rewrites chain.idx from the in-memory sorted records: a
+// 256-entry fanout table of cumulative counts by the first index byte,
+// followed by the sorted fixed-width records themselves.
+func (c *Chain) writeIndex() error {
+	var fanout [256]uint32
+	for _, rec := range c.records {
+		fanout[rec.key[0]]++
+	}
+	for i := 1; i < 256; i++ {
+		fanout[i] += fanout[i-1]
+	}
+
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.BigEndian, fanout); err != nil {
+		return err
+	}
+	for _, rec := range c.records {
+		buf.Write(rec.key[:])
+		var tail [12]byte
+		binary.BigEndian.PutUint64(tail[:8], rec.offset)
+		binary.BigEndian.PutUint32(tail[8:], rec.length)
+		buf.Write(tail[:])
+	}
+	return os.WriteFile(c.idxPath, buf.Bytes(), 0600)
+}
+
+// SignatureVerifier validates a single WriteMarker's signature. Verify
+// takes one instead of a bare public key so tests can exercise chain-break
+// detection with a stub that always succeeds, independent of whatever a
+// real marker.WriteMarker.VerifySignature implementation requires.
+type SignatureVerifier func(wm *marker.WriteMarker) error
+
+// VerifyWithPublicKey builds a SignatureVerifier that checks
+// wm.VerifySignature against publicKey (e.g. the client's own key).
+func VerifyWithPublicKey(publicKey string) SignatureVerifier {
+	return func(wm *marker.WriteMarker) error {
+		return wm.VerifySignature(publicKey)
+	}
+}
+
+// Verify walks the chain end-to-end, per blobber, checking that every
+// entry's signature passes verifySignature and that each entry's
+// PreviousAllocationRoot matches its predecessor's AllocationRoot.
+func (c *Chain) Verify(verifySignature SignatureVerifier) error {
+	c.mu.Lock()
+	logPath := c.logPath
+	c.mu.Unlock()
+
+	f, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	byBlobber := make(map[string]string) // blobberID -> last seen AllocationRoot, in log order
+	r := bufio.NewReader(f)
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(lenBuf[:])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		if err := verifySignature(entry.WM); err != nil {
+			return fmt.Errorf("wmchain: signature verification failed for blobber %s, root %s: %w", entry.BlobberID, entry.WM.AllocationRoot, err)
+		}
+		if prev, ok := byBlobber[entry.BlobberID]; ok && entry.WM.PreviousAllocationRoot != prev {
+			return fmt.Errorf("wmchain: chain break for blobber %s: expected previous root %s, got %s",
+				entry.BlobberID, prev, entry.WM.PreviousAllocationRoot)
+		}
+		byBlobber[entry.BlobberID] = entry.WM.AllocationRoot
+	}
+}
+
+// Export writes the full chain log verbatim, for copying to another
+// device.
+func (c *Chain) Export(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.Open(c.logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Import merges entries read from r (in the Export format) into this
+// chain, skipping any AllocationRoot already present so importing the same
+// export twice is a no-op.
+func (c *Chain) Import(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(br, lenBuf[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		length := binary.BigEndian.Uint32(lenBuf[:])
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return err
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		if _, ok := c.LookupByRoot(entry.WM.AllocationRoot); ok {
+			continue
+		}
+		if err := c.Append(entry.BlobberID, entry.WM); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package wmchain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/0chain/gosdk/zboxcore/marker"
+)
+
+func wm(root, prev string, ts int64) *marker.WriteMarker {
+	return &marker.WriteMarker{AllocationRoot: root, PreviousAllocationRoot: prev, Timestamp: ts}
+}
+
+func TestAppendAndTip(t *testing.T) {
+	c, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r2", "r1", 2)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	tip, ok := c.Tip("blobber1")
+	if !ok || tip.AllocationRoot != "r2" {
+		t.Fatalf("expected tip r2, got %+v (ok=%v)", tip, ok)
+	}
+}
+
+func TestLookupByRootSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	reopened, err := Open(dir, "alloc1")
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	entry, ok := reopened.LookupByRoot("r1")
+	if !ok || entry.WM.AllocationRoot != "r1" {
+		t.Fatalf("expected to find r1 after reopen, got %+v (ok=%v)", entry, ok)
+	}
+}
+
+func TestCheckNotRollbackDetectsStaleReport(t *testing.T) {
+	c, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r2", "r1", 2)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if err := c.CheckNotRollback("blobber1", wm("r2", "r1", 2)); err != nil {
+		t.Fatalf("expected current tip to pass, got %v", err)
+	}
+	if err := c.CheckNotRollback("blobber1", wm("r3", "r2", 3)); err != nil {
+		t.Fatalf("expected forward progress to pass, got %v", err)
+	}
+
+	err = c.CheckNotRollback("blobber1", wm("r1", "", 1))
+	if err == nil {
+		t.Fatal("expected rollback to a stale marker to be detected")
+	}
+	if _, ok := err.(*RollbackDetectedError); !ok {
+		t.Fatalf("expected *RollbackDetectedError, got %T: %v", err, err)
+	}
+}
+
+// alwaysValidSignature stubs out signature verification so these tests
+// exercise chain-break detection itself, not marker.WriteMarker's signing.
+func alwaysValidSignature(wm *marker.WriteMarker) error { return nil }
+
+func TestVerifyPassesOnIntactChain(t *testing.T) {
+	c, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r2", "r1", 2)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := c.Verify(alwaysValidSignature); err != nil {
+		t.Fatalf("expected intact chain to verify, got %v", err)
+	}
+}
+
+func TestVerifyDetectsChainBreak(t *testing.T) {
+	c, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	// r2 should chain from r1 but claims an unrelated previous root.
+	if err := c.Append("blobber1", wm("r2", "not-r1", 2)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := c.Verify(alwaysValidSignature); err == nil {
+		t.Fatal("expected Verify to detect the broken chain")
+	}
+}
+
+func TestVerifyDetectsBadSignature(t *testing.T) {
+	c, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := c.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	wantErr := errors.New("bad signature")
+	if err := c.Verify(func(wm *marker.WriteMarker) error { return wantErr }); err == nil {
+		t.Fatal("expected Verify to surface a signature verification failure")
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := src.Append("blobber1", wm("r1", "", 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst, err := Open(t.TempDir(), "alloc1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := dst.Import(&buf); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if _, ok := dst.LookupByRoot("r1"); !ok {
+		t.Fatal("expected imported entry to be found by root")
+	}
+}
@@ -0,0 +1,47 @@
+package wmchain
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store hands out the Chain for a given allocation, opening it on first
+// use and reusing it for the lifetime of the process.
+type Store struct {
+	mu      sync.Mutex
+	baseDir string
+	chains  map[string]*Chain
+}
+
+// NewStore creates a Store rooted at baseDir; each allocation's chain
+// lives under baseDir/<allocationID>/.
+func NewStore(baseDir string) *Store {
+	return &Store{baseDir: baseDir, chains: make(map[string]*Chain)}
+}
+
+// DefaultBaseDir returns ~/.zcn/wmchain, the directory the zbox client
+// tooling already uses for other per-user state.
+func DefaultBaseDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".zcn", "wmchain"), nil
+}
+
+// Chain returns the Chain for allocationID, opening it if this is the
+// first request for it.
+func (s *Store) Chain(allocationID string) (*Chain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.chains[allocationID]; ok {
+		return c, nil
+	}
+	c, err := Open(s.baseDir, allocationID)
+	if err != nil {
+		return nil, err
+	}
+	s.chains[allocationID] = c
+	return c, nil
+}